@@ -0,0 +1,187 @@
+package core
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"chain/errors"
+	"chain/net/http/httpjson"
+)
+
+var errIncompatibleRPCVersion = errors.New("peer advertises an incompatible crosscore RPC version")
+
+// peerProbeInterval is how often probePeersLoop refreshes peerCache.
+const peerProbeInterval = 10 * time.Second
+
+// raftHeartbeatTimeout bounds how stale a raft member's last heartbeat
+// can be before listClusterMembers reports it unreachable.
+const raftHeartbeatTimeout = 15 * time.Second
+
+var (
+	peerCacheMu sync.Mutex
+	peerCache   = map[string]*ClusterMember{}
+)
+
+// ClusterMember describes the current state of one member of the raft
+// cluster or one configured cross-core peer (generator, signer, or known
+// follower), as observed by this core.
+type ClusterMember struct {
+	Addr                string    `json:"addr"`
+	Role                string    `json:"role"` // "raft", "generator", "signer", "follower"
+	CrosscoreRPCVersion int       `json:"crosscore_rpc_version"`
+	LastSeen            time.Time `json:"last_seen"`
+	RTTMs               int64     `json:"rtt_ms"`
+	BlockHeight         uint64    `json:"block_height"`
+	RaftState           string    `json:"raft_state,omitempty"`
+	HealthStatus        string    `json:"health_status"` // "healthy" or "unreachable"
+}
+
+// crosscorePingResponse is the payload returned by the /crosscore/ping
+// endpoint: the fields a peer reports about itself (crosscore RPC
+// version, block height, raft state) that /list-cluster-members records
+// for each probed peer, plus the peer's committed signer epoch.
+type crosscorePingResponse struct {
+	CrosscoreRPCVersion int    `json:"crosscore_rpc_version"`
+	BlockHeight         uint64 `json:"block_height"`
+	RaftState           string `json:"raft_state,omitempty"`
+	SignerEpoch         uint64 `json:"signer_epoch"`
+}
+
+// crosscorePing answers a peer's probe with this core's current state,
+// including the signer epoch it has committed, so a follower probing its
+// generator (see core/fetch.Run) can tell a generator that's fallen
+// behind the latest signer-set rotation from one that's caught up.
+func (a *API) crosscorePing(ctx context.Context) (crosscorePingResponse, error) {
+	epoch, err := a.currentSignerEpoch(ctx)
+	if err != nil {
+		return crosscorePingResponse{}, err
+	}
+	return crosscorePingResponse{
+		CrosscoreRPCVersion: crosscoreRPCVersion,
+		BlockHeight:         a.chain.Height(),
+		RaftState:           a.leader.State().String(),
+		SignerEpoch:         epoch.Epoch,
+	}, nil
+}
+
+// listClusterMembers returns a health record for every raft member and
+// every configured cross-core peer. Raft members piggyback their health
+// on the existing raft heartbeat rather than a separate probe; configured
+// peers are read from the cache probePeersLoop maintains in the
+// background, so the request never blocks on a cross-core round trip.
+func (a *API) listClusterMembers(ctx context.Context) ([]*ClusterMember, error) {
+	members := a.sdb.RaftService().State().Members()
+	peers := a.config.KnownPeers()
+
+	out := make([]*ClusterMember, 0, len(members)+len(peers))
+	for _, rm := range members {
+		cm := &ClusterMember{
+			Addr:      rm.Addr,
+			Role:      "raft",
+			RaftState: rm.State,
+			LastSeen:  rm.LastSeen,
+		}
+		if time.Since(rm.LastSeen) > raftHeartbeatTimeout {
+			cm.HealthStatus = "unreachable"
+		} else {
+			cm.HealthStatus = "healthy"
+		}
+		out = append(out, cm)
+	}
+
+	peerCacheMu.Lock()
+	for _, peer := range peers {
+		if cached, ok := peerCache[peer.Addr]; ok {
+			cm := *cached
+			out = append(out, &cm)
+		} else {
+			out = append(out, &ClusterMember{Addr: peer.Addr, Role: peer.Role, HealthStatus: "unreachable"})
+		}
+	}
+	peerCacheMu.Unlock()
+	return out, nil
+}
+
+// probePeersLoop periodically refreshes peerCache by probing every
+// configured cross-core peer's /crosscore/ping endpoint, so
+// listClusterMembers can answer from the cache instead of blocking the
+// request on a round trip per peer while it waits on a single slow or
+// unreachable one. It runs until ctx is done, the same way
+// core/fetch.Run polls the generator on its own background cadence.
+func (a *API) probePeersLoop(ctx context.Context) {
+	ticker := time.NewTicker(peerProbeInterval)
+	defer ticker.Stop()
+	for {
+		a.refreshPeerCache(ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// refreshPeerCache probes every configured cross-core peer concurrently
+// and stores the results in peerCache.
+func (a *API) refreshPeerCache(ctx context.Context) {
+	peers := a.config.KnownPeers()
+	var wg sync.WaitGroup
+	for _, peer := range peers {
+		peer := peer
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			cm := &ClusterMember{Addr: peer.Addr, Role: peer.Role}
+			a.probeClusterMember(ctx, cm)
+
+			peerCacheMu.Lock()
+			peerCache[peer.Addr] = cm
+			peerCacheMu.Unlock()
+		}()
+	}
+	wg.Wait()
+}
+
+// probeClusterMember fills in rtt, block height, and health status for
+// a cluster member by calling its /crosscore/ping endpoint.
+func (a *API) probeClusterMember(ctx context.Context, cm *ClusterMember) {
+	start := time.Now()
+	var resp crosscorePingResponse
+	err := a.forwardTo(ctx, cm.Addr, "/crosscore/ping", nil, &resp)
+	if err != nil {
+		cm.HealthStatus = "unreachable"
+		return
+	}
+	cm.RTTMs = time.Since(start).Milliseconds()
+	cm.LastSeen = time.Now()
+	cm.CrosscoreRPCVersion = resp.CrosscoreRPCVersion
+	cm.BlockHeight = resp.BlockHeight
+	if resp.RaftState != "" {
+		cm.RaftState = resp.RaftState
+	}
+	cm.HealthStatus = "healthy"
+}
+
+// removeClusterMember evicts a raft member from the cluster, e.g. after
+// it's been decommissioned. It does not affect cross-core peers, which
+// are managed through core/config.
+func (a *API) removeClusterMember(ctx context.Context, req struct{ Addr string }) error {
+	return a.sdb.RaftService().Remove(req.Addr)
+}
+
+// checkPeerRPCVersion rejects a joining peer whose crosscore RPC version
+// is incompatible with this core's, so a stale core can't join a cluster
+// that's already speaking a newer wire protocol.
+func checkPeerRPCVersion(peerVersion int) error {
+	if peerVersion != crosscoreRPCVersion {
+		return errors.Wrapf(errIncompatibleRPCVersion, "peer version %d, local version %d", peerVersion, crosscoreRPCVersion)
+	}
+	return nil
+}
+
+// forwardTo issues a cross-core RPC to addr, the same way forwardToLeader
+// forwards to the current leader.
+func (a *API) forwardTo(ctx context.Context, addr, path string, body, resp interface{}) error {
+	return httpjson.Call(ctx, a.httpClient, "https://"+addr+path, "GET", body, resp)
+}
@@ -0,0 +1,103 @@
+// Package config manages the configuration of a single Core process:
+// whether it's a generator or follower, whether it signs blocks, which
+// generator it follows, and the handful of other settings that are set
+// once at /configure time and then read for the lifetime of the
+// process.
+package config
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+
+	"chain/database/pg"
+	"chain/database/sinkdb"
+	"chain/errors"
+)
+
+// Build-time metadata. These are overridden via -ldflags by the release
+// build; the zero values are what a developer build reports.
+var (
+	Version     = "devel"
+	BuildCommit = "?"
+	BuildDate   = "?"
+	BuildConfig = "?"
+)
+
+// configKey is where the configuration is stored in sinkdb, so that
+// every core in a cluster sees the same configuration without a
+// separate config-replication path.
+const configKey = "/core/config"
+
+// Config holds a Core's configuration, as submitted to /configure and
+// persisted in sinkdb.
+type Config struct {
+	Id                   string `json:"id"`
+	IsGenerator          bool   `json:"is_generator"`
+	IsSigner             bool   `json:"is_signer"`
+	GeneratorUrl         string `json:"generator_url"`
+	GeneratorAccessToken string `json:"generator_access_token"`
+	BlockchainId         string `json:"blockchain_id"`
+	ConfiguredAt         uint64 `json:"configured_at"` // ms since the epoch
+	MaxIssuanceWindowMs  uint64 `json:"max_issuance_window_ms"`
+
+	// MaxReorgDepth bounds how far back the follower reorg detector
+	// (core/fetch) will walk to find a common ancestor with the
+	// generator's chain before refusing to roll back and halting
+	// instead. Zero disables the limit.
+	MaxReorgDepth uint64 `json:"max_reorg_depth"`
+
+	// IsValidator runs this core as a watchtower alongside a follower
+	// core: every block is independently re-executed against
+	// protocol/validation rather than trusted on the generator's
+	// signature, and failures are published as FraudAlerts.
+	IsValidator bool `json:"is_validator"`
+
+	// Peers lists every cross-core peer configured for this core: the
+	// generator, signers, and known followers. /list-cluster-members
+	// combines this with raft heartbeat state for operator visibility.
+	Peers []Peer `json:"peers"`
+}
+
+// Peer is one cross-core peer configured at /configure time.
+type Peer struct {
+	Addr string `json:"addr"`
+	Role string `json:"role"` // "generator", "signer", "follower"
+}
+
+// KnownPeers returns every cross-core peer configured for this core.
+func (c *Config) KnownPeers() []Peer {
+	return c.Peers
+}
+
+// Configure persists x as the core's configuration in sinkdb; nothing
+// is written to Postgres through db. Callers re-exec the process after Configure returns so the
+// new configuration takes effect from a clean start.
+func Configure(ctx context.Context, db pg.DB, sdb *sinkdb.DB, httpClient *http.Client, x *Config) error {
+	b, err := json.Marshal(x)
+	if err != nil {
+		return errors.Wrap(err, "encoding core config")
+	}
+	return sdb.Exec(ctx,
+		sinkdb.IfNotExists(configKey),
+		sinkdb.Set(configKey, &sinkdb.Bytes{Value: b}),
+	)
+}
+
+// Load reads the persisted configuration from sinkdb, or returns
+// (nil, nil) if the core has never been configured.
+func Load(ctx context.Context, sdb *sinkdb.DB) (*Config, error) {
+	var raw sinkdb.Bytes
+	found, err := sdb.Get(ctx, configKey, &raw)
+	if err != nil {
+		return nil, errors.Wrap(err, "loading core config")
+	}
+	if !found {
+		return nil, nil
+	}
+	var c Config
+	if err := json.Unmarshal(raw.Value, &c); err != nil {
+		return nil, errors.Wrap(err, "decoding core config")
+	}
+	return &c, nil
+}
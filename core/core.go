@@ -33,7 +33,10 @@ var (
 )
 
 const (
-	crosscoreRPCVersion = 3
+	// crosscoreRPCVersion 4 adds the signer-set epoch to cross-core
+	// handshakes, so followers can reject blocks signed by a stale
+	// committee during a signer rotation (see signer_rotation.go).
+	crosscoreRPCVersion = 4
 	clusterIDKey        = `/core/cluster_id`
 )
 
@@ -128,6 +131,24 @@ func (a *API) leaderInfo(ctx context.Context) (map[string]interface{}, error) {
 		"health":                            a.health(),
 	}
 
+	if reorg := fetch.LastReorg(); reorg != nil {
+		m["last_reorg"] = reorg
+	}
+
+	signerEpoch, err := a.currentSignerEpoch(ctx)
+	if err != nil {
+		return nil, err
+	}
+	m["signer_epoch"] = signerEpoch
+
+	if a.config.IsValidator {
+		alerts, err := a.listFraudAlerts(ctx)
+		if err != nil {
+			return nil, err
+		}
+		m["fraud_alerts"] = alerts
+	}
+
 	// Add in snapshot information if we're downloading a snapshot.
 	if snapshot != nil {
 		downloadedBytes, totalBytes := snapshot.Progress()
@@ -151,6 +172,8 @@ func (a *API) configure(ctx context.Context, x *config.Config) error {
 		x.MaxIssuanceWindowMs = bc.DurationMillis(24 * time.Hour)
 	}
 
+	fetch.MaxReorgDepth = x.MaxReorgDepth
+
 	err := config.Configure(ctx, a.db, a.sdb, a.httpClient, x)
 	if err != nil {
 		return err
@@ -193,6 +216,17 @@ func (a *API) joinCluster(ctx context.Context, x struct {
 	}
 
 	bootURL := fmt.Sprintf("https://%s", x.BootAddress)
+
+	var ping crosscorePingResponse
+	err = a.forwardTo(ctx, x.BootAddress, "/crosscore/ping", nil, &ping)
+	if err != nil {
+		return errors.Sub(errInvalidAddr, err)
+	}
+	err = checkPeerRPCVersion(ping.CrosscoreRPCVersion)
+	if err != nil {
+		return err
+	}
+
 	return a.sdb.RaftService().Join(bootURL)
 }
 
@@ -0,0 +1,153 @@
+// Package fetch maintains a follower core's view of the generator: its
+// current block height, and (via reorg.go) whether the generator's
+// chain has diverged from the local one and needs rolling back to a
+// common ancestor.
+package fetch
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"chain/errors"
+	"chain/log"
+	"chain/protocol/bc/legacy"
+)
+
+// pollInterval is how often Run polls the generator for its current
+// height and checks for a reorg. It mirrors the cadence a follower
+// already uses to stay caught up with the generator's block feed.
+const pollInterval = 3 * time.Second
+
+var (
+	generatorHeightMu sync.Mutex
+	generatorHeight   uint64
+	generatorFetched  time.Time
+)
+
+// GeneratorHeight returns the generator's block height as of the most
+// recent successful poll, and the time it was observed. The zero time
+// means no successful poll has happened yet.
+func GeneratorHeight() (uint64, time.Time) {
+	generatorHeightMu.Lock()
+	defer generatorHeightMu.Unlock()
+	return generatorHeight, generatorFetched
+}
+
+func setGeneratorHeight(height uint64) {
+	generatorHeightMu.Lock()
+	generatorHeight = height
+	generatorFetched = time.Now()
+	generatorHeightMu.Unlock()
+}
+
+// heightFetcher is satisfied by the generator cross-core RPC client; it
+// reports the generator's current block height.
+type heightFetcher interface {
+	Height(ctx context.Context) (uint64, error)
+}
+
+// blockSource fetches a full block by height, for callers (like a
+// watchtower Validator) that need more than just the block's hash.
+type blockSource interface {
+	BlockAt(ctx context.Context, height uint64) (*legacy.Block, error)
+}
+
+// Validator independently re-validates a fetched block rather than
+// trusting the generator's signature. The watchtower in
+// core/watchtower.go implements it; Run uses whichever Validator its
+// caller passes in.
+type Validator interface {
+	ValidateBlock(ctx context.Context, b *legacy.Block, initialBlockHash legacy.Hash) error
+}
+
+// epochFetcher is satisfied by the generator cross-core RPC client; it
+// reports the signer epoch the generator has most recently committed,
+// the same value crosscorePingResponse carries.
+type epochFetcher interface {
+	SignerEpoch(ctx context.Context) (uint64, error)
+}
+
+// ErrStaleSignerCommittee is logged by Run, which then skips that poll,
+// when the generator is still advertising a signer epoch older than the one this
+// core has already committed locally: the generator hasn't caught up to
+// the latest rotation, so its blocks can't be trusted not to carry
+// signatures from a committee that's since been replaced.
+var ErrStaleSignerCommittee = errors.New("generator is advertising a stale signer epoch")
+
+// Run polls remote for the generator's current height once per
+// pollInterval, records it via GeneratorHeight, and invokes checkReorg
+// with the newly observed heights so a diverging generator chain is
+// detected and rolled back (under lock, with applier replaying forward
+// again) to a common ancestor on the same cadence that the height is
+// refreshed. If validator is non-nil, every block from localHeight()+1
+// up to the generator's height is also fetched via blocks and
+// independently re-executed through validator, in order, before the
+// height is trusted; Run stops advancing at the first one that fails,
+// rather than only checking the newest block each tick. If epochs and
+// localEpoch are non-nil, Run also refuses to advance past a generator
+// that's still advertising a signer epoch older than the one this core
+// has committed locally. It runs until ctx is done.
+func Run(ctx context.Context, lock leaderLock, local, remote blockHasher, remoteHeights heightFetcher, localHeight func() uint64, blocks blockSource, applier chainApplier, validator Validator, epochs epochFetcher, localEpoch func(ctx context.Context) (uint64, error), initialBlockHash legacy.Hash, stores ...rollbackStore) {
+	ticker := time.NewTicker(pollInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			remoteHeight, err := remoteHeights.Height(ctx)
+			if err != nil {
+				log.Printf(ctx, "fetching generator height: %s", err)
+				continue
+			}
+
+			if epochs != nil && localEpoch != nil {
+				remoteEpoch, err := epochs.SignerEpoch(ctx)
+				if err != nil {
+					log.Printf(ctx, "fetching generator signer epoch: %s", err)
+					continue
+				}
+				curEpoch, err := localEpoch(ctx)
+				if err != nil {
+					log.Printf(ctx, "reading local signer epoch: %s", err)
+					continue
+				}
+				if remoteEpoch < curEpoch {
+					log.Printf(ctx, "%s: generator epoch %d, local epoch %d", ErrStaleSignerCommittee, remoteEpoch, curEpoch)
+					continue
+				}
+			}
+
+			err = checkReorg(ctx, lock, local, remote, localHeight(), remoteHeight, applier, stores...)
+			if err != nil {
+				log.Printf(ctx, "reorg check failed: %s", err)
+				continue
+			}
+
+			if validator != nil && blocks != nil {
+				advanceTo := remoteHeight
+				fetchFailed := false
+				for h := localHeight() + 1; h <= remoteHeight; h++ {
+					b, err := blocks.BlockAt(ctx, h)
+					if err != nil {
+						log.Printf(ctx, "fetching block %d for independent validation: %s", h, err)
+						fetchFailed = true
+						break
+					}
+					if err := validator.ValidateBlock(ctx, b, initialBlockHash); err != nil {
+						log.Printf(ctx, "independent validation of block %d failed, refusing to advance: %s", h, err)
+						advanceTo = h - 1
+						break
+					}
+				}
+				if fetchFailed {
+					continue
+				}
+				remoteHeight = advanceTo
+			}
+
+			setGeneratorHeight(remoteHeight)
+		}
+	}
+}
@@ -0,0 +1,173 @@
+package fetch
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"chain/errors"
+	"chain/log"
+)
+
+// ErrReorgTooDeep is returned by checkReorg when rolling back to the
+// agreeing height found by its exponential search would undo more than
+// MaxReorgDepth blocks. That height can lie below the latest common
+// ancestor, so the check can trip on a divergence no deeper than
+// MaxReorgDepth. The
+// caller should halt rather than roll back, since a divergence this deep
+// is more likely to indicate a compromised or forked generator than a
+// routine reorg.
+var ErrReorgTooDeep = errors.New("reorg exceeds max reorg depth")
+
+// MaxReorgDepth bounds how far back the reorg detector will walk to find
+// a common ancestor. It is set from config.Config.MaxReorgDepth when the
+// fetch loop starts; zero disables the limit.
+var MaxReorgDepth uint64
+
+// Reorg describes a completed rollback of local state to a common
+// ancestor with the generator's chain. It's surfaced through leaderInfo
+// and health() so operators can see it without grepping logs.
+type Reorg struct {
+	Height uint64    `json:"height"` // height of the common ancestor rolled back to
+	Depth  uint64    `json:"depth"`  // number of blocks rolled back
+	At     time.Time `json:"at"`
+}
+
+var (
+	reorgMu   sync.Mutex
+	lastReorg *Reorg
+)
+
+// LastReorg returns the most recently completed reorg, or nil if none has
+// happened since the process started.
+func LastReorg() *Reorg {
+	reorgMu.Lock()
+	defer reorgMu.Unlock()
+	return lastReorg
+}
+
+func setLastReorg(r *Reorg) {
+	reorgMu.Lock()
+	lastReorg = r
+	reorgMu.Unlock()
+}
+
+// blockHasher is satisfied by the local block store and by the generator
+// RPC client; checkReorg uses it to compare chains without caring which
+// side a hash came from.
+type blockHasher interface {
+	BlockHash(ctx context.Context, height uint64) (hash [32]byte, err error)
+}
+
+// rollbackStore is implemented by the local block store, UTXO snapshot,
+// and indexer state. checkReorg calls RollbackTo on each under the
+// leader lock once a common ancestor is found.
+type rollbackStore interface {
+	RollbackTo(ctx context.Context, height uint64) error
+}
+
+// leaderLock is satisfied by the process's leader-election lock.
+// checkReorg holds it for the whole rollback-and-replay, not just the
+// rollback, so the normal block-application path (which runs under the
+// same lock) can't write to the block store, UTXO snapshot, or indexer
+// state while they're being rolled back and brought back forward.
+type leaderLock interface {
+	Lock(ctx context.Context) (unlock func(), err error)
+}
+
+// chainApplier re-applies a single block of the generator's canonical
+// chain to local state. Once every store has been rolled back to the
+// common ancestor, checkReorg drives it forward again up to
+// remoteHeight, so a reorg leaves local state caught up rather than
+// merely stopped at the ancestor height.
+type chainApplier interface {
+	ApplyBlock(ctx context.Context, height uint64) error
+}
+
+// checkReorg compares the generator's and the local chain's block hash at
+// height min(local, remote). On a mismatch it acquires lock, walks
+// backward in exponential steps (1, 2, 4, 8, ...) until it finds a height
+// where the hashes agree, rolls every store in stores back to that
+// height, and then drives applier forward again up to remoteHeight
+// before releasing the lock, so the normal block-application path never
+// observes local state mid-rollback.
+//
+// checkReorg is invoked from the same poll loop that maintains
+// GeneratorHeight, once per successful poll.
+func checkReorg(ctx context.Context, lock leaderLock, local, remote blockHasher, localHeight, remoteHeight uint64, applier chainApplier, stores ...rollbackStore) error {
+	height := localHeight
+	if remoteHeight < height {
+		height = remoteHeight
+	}
+	if height == 0 {
+		return nil
+	}
+
+	localHash, err := local.BlockHash(ctx, height)
+	if err != nil {
+		return errors.Wrap(err, "reading local block hash")
+	}
+	remoteHash, err := remote.BlockHash(ctx, height)
+	if err != nil {
+		return errors.Wrap(err, "reading generator block hash")
+	}
+	if localHash == remoteHash {
+		return nil // no reorg
+	}
+
+	log.Printf(ctx, "chain reorg detected at height %d; searching for common ancestor", height)
+
+	unlock, err := lock.Lock(ctx)
+	if err != nil {
+		return errors.Wrap(err, "acquiring leader lock for reorg")
+	}
+	defer unlock()
+
+	var step uint64 = 1
+	ancestor := height
+	for {
+		if ancestor <= step {
+			ancestor = 0
+			break
+		}
+		ancestor -= step
+
+		lh, err := local.BlockHash(ctx, ancestor)
+		if err != nil {
+			return errors.Wrap(err, "reading local block hash")
+		}
+		rh, err := remote.BlockHash(ctx, ancestor)
+		if err != nil {
+			return errors.Wrap(err, "reading generator block hash")
+		}
+		if lh == rh {
+			break
+		}
+
+		if MaxReorgDepth > 0 && height-ancestor >= MaxReorgDepth {
+			return errors.Wrap(ErrReorgTooDeep, "refusing to roll back")
+		}
+		step *= 2
+	}
+
+	depth := height - ancestor
+	if MaxReorgDepth > 0 && depth > MaxReorgDepth {
+		return errors.Wrap(ErrReorgTooDeep, "refusing to roll back")
+	}
+
+	for _, s := range stores {
+		if err := s.RollbackTo(ctx, ancestor); err != nil {
+			return errors.Wrap(err, "rolling back to common ancestor")
+		}
+	}
+
+	setLastReorg(&Reorg{Height: ancestor, Depth: depth, At: time.Now()})
+	log.Printf(ctx, "rolled back %d blocks to height %d; re-applying generator's chain", depth, ancestor)
+
+	for h := ancestor + 1; h <= remoteHeight; h++ {
+		if err := applier.ApplyBlock(ctx, h); err != nil {
+			return errors.Wrap(err, "re-applying generator's chain after rollback")
+		}
+	}
+	return nil
+}
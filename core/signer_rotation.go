@@ -0,0 +1,312 @@
+package core
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"chain/crypto/ed25519"
+	"chain/database/sinkdb"
+	"chain/errors"
+)
+
+// Signer-change proposals are stored in sinkdb under this prefix, keyed by
+// proposal ID, so that every core in the cluster observes the same set of
+// pending changes and ratifications.
+const signerChangePrefix = "/core/signer-changes/"
+
+var (
+	errNotSigner           = errors.New("only a signer may ratify a signer-change proposal")
+	errNotGenerator        = errors.New("only the generator may propose a signer-change")
+	errUnknownChange       = errors.New("signer-change proposal not found")
+	errChangeAlreadyVoted  = errors.New("this signer has already ratified the proposal")
+	errPastEffectiveHeight = errors.New("effective height must be in the future")
+	errBadRatification     = errors.New("ratification signature does not verify against the claimed pubkey")
+	errTooManyEpochRetries = errors.New("too many concurrent signer-epoch commits")
+)
+
+// signerEpochPrefix stores every committed signer epoch, one per key,
+// numbered by epoch. Committing epoch N+1 is a conditional write
+// (sinkdb.IfNotExists) against that epoch's own key rather than a blind
+// overwrite of a single shared key, so two proposals reaching quorum at
+// the same time can't silently clobber one another: only the first one
+// to create epoch N+1 wins, and the loser retries against the epoch it
+// just lost to. Cores read the prefix to reject blocks signed by a
+// stale committee.
+const signerEpochPrefix = "/core/signer-epoch/"
+
+// signerEpochKeyFor returns the sinkdb key epoch is committed under.
+// Epoch numbers are zero-padded so that key order matches epoch order;
+// currentSignerEpoch still compares Epoch values rather than relying on
+// that order.
+func signerEpochKeyFor(epoch uint64) string {
+	return fmt.Sprintf("%s%020d", signerEpochPrefix, epoch)
+}
+
+// SignerEpoch is the currently-effective block-signer quorum. It's
+// advanced by voteSignerChange once a proposal's ratifications reach
+// quorum, and is what cross-core RPC advertises so followers can tell a
+// stale committee from the current one.
+type SignerEpoch struct {
+	Epoch           uint64         `json:"epoch"`
+	Signers         []SignerUpdate `json:"signers"`
+	EffectiveHeight uint64         `json:"effective_height"`
+}
+
+// SignerUpdate describes a single addition, removal, or power change for a
+// member of the block-signer quorum. It follows the ABCI validator-update
+// pattern: the generator emits a diff, and the diff is applied atomically
+// once ratified.
+type SignerUpdate struct {
+	Pubkey ed25519.PublicKey `json:"pubkey"`
+	Power  uint32            `json:"power"` // 0 means remove
+}
+
+// Ratification is one signer's signature over a proposal's
+// ratificationMessage, binding the signature to the pubkey it claims to
+// come from so voteSignerChange can verify it before counting it toward
+// quorum.
+type Ratification struct {
+	Pubkey    ed25519.PublicKey `json:"pubkey"`
+	Signature []byte            `json:"signature"`
+}
+
+// SignerChangeProposal is a proposed rotation of the block-signer quorum.
+// It becomes effective at EffectiveHeight once len(Ratifications) reaches
+// Quorum.
+type SignerChangeProposal struct {
+	ID              string         `json:"id"`
+	Updates         []SignerUpdate `json:"updates"`
+	Quorum          uint32         `json:"quorum"`
+	EffectiveHeight uint64         `json:"effective_height"`
+	ProposedAt      time.Time      `json:"proposed_at"`
+	Ratifications   []Ratification `json:"ratifications"`
+	Committed       bool           `json:"committed"` // true once quorum was reached and the epoch was applied
+}
+
+// ratificationMessage returns the bytes that each signer signs to ratify
+// the proposal. It binds the signature to the proposal ID and the
+// effective height so a ratification can't be replayed against a
+// different proposal.
+func (p *SignerChangeProposal) ratificationMessage() []byte {
+	msg := []byte(p.ID)
+	var height [8]byte
+	binary.BigEndian.PutUint64(height[:], p.EffectiveHeight)
+	return append(msg, height[:]...)
+}
+
+// proposeSignerChange lets the generator propose a new signer set. The
+// proposal is stored in sinkdb so that every core observes the same
+// pending changes; it takes effect once Quorum distinct pubkeys have
+// ratified it through voteSignerChange. voteSignerChange does not check
+// that a ratifying pubkey belongs to the current signer set.
+func (a *API) proposeSignerChange(ctx context.Context, req struct {
+	Updates         []SignerUpdate `json:"updates"`
+	Quorum          uint32         `json:"quorum"`
+	EffectiveHeight uint64         `json:"effective_height"`
+}) (*SignerChangeProposal, error) {
+	if !a.config.IsGenerator {
+		return nil, errNotGenerator
+	}
+	if req.EffectiveHeight <= a.chain.Height() {
+		return nil, errPastEffectiveHeight
+	}
+
+	var idBytes [16]byte
+	_, err := rand.Read(idBytes[:])
+	if err != nil {
+		return nil, errors.Wrap(err, "generating proposal id")
+	}
+
+	p := &SignerChangeProposal{
+		ID:              hex.EncodeToString(idBytes[:]),
+		Updates:         req.Updates,
+		Quorum:          req.Quorum,
+		EffectiveHeight: req.EffectiveHeight,
+		ProposedAt:      time.Now(),
+	}
+
+	b, err := json.Marshal(p)
+	if err != nil {
+		return nil, errors.Wrap(err, "encoding signer-change proposal")
+	}
+
+	err = a.sdb.Exec(ctx,
+		sinkdb.IfNotExists(signerChangePrefix+p.ID),
+		sinkdb.Set(signerChangePrefix+p.ID, &sinkdb.Bytes{Value: b}),
+	)
+	if err != nil {
+		return nil, errors.Wrap(err, "storing signer-change proposal")
+	}
+	return p, nil
+}
+
+// voteSignerChange records a signer's ratification of a pending
+// proposal, after verifying that Signature is a valid ed25519 signature
+// by Pubkey over the proposal's ratification message. Once the
+// ratification count reaches the proposal's quorum, the change is
+// committed: the updates are applied to a new SignerEpoch so that
+// cross-core RPC advertises the new committee and followers can reject
+// blocks signed by a stale one.
+func (a *API) voteSignerChange(ctx context.Context, req struct {
+	ProposalID string            `json:"proposal_id"`
+	Pubkey     ed25519.PublicKey `json:"pubkey"`
+	Signature  []byte            `json:"signature"`
+}) error {
+	if !a.config.IsSigner {
+		return errNotSigner
+	}
+
+	p, err := a.loadSignerChange(ctx, req.ProposalID)
+	if err != nil {
+		return err
+	}
+
+	if !ed25519.Verify(req.Pubkey, p.ratificationMessage(), req.Signature) {
+		return errBadRatification
+	}
+
+	for _, r := range p.Ratifications {
+		if string(r.Pubkey) == string(req.Pubkey) {
+			return errChangeAlreadyVoted
+		}
+	}
+	p.Ratifications = append(p.Ratifications, Ratification{Pubkey: req.Pubkey, Signature: req.Signature})
+
+	if !p.Committed && uint32(len(p.Ratifications)) >= p.Quorum {
+		err := a.applySignerChange(ctx, p)
+		if err != nil {
+			return errors.Wrap(err, "applying signer-change proposal")
+		}
+		p.Committed = true
+	}
+
+	b, err := json.Marshal(p)
+	if err != nil {
+		return errors.Wrap(err, "encoding signer-change proposal")
+	}
+	return a.sdb.Exec(ctx, sinkdb.Set(signerChangePrefix+p.ID, &sinkdb.Bytes{Value: b}))
+}
+
+// maxEpochCommitAttempts bounds how many times applySignerChange will
+// re-read the current epoch and retry after losing a race to commit the
+// next one, before giving up and returning an error to the caller.
+const maxEpochCommitAttempts = 5
+
+// applySignerChange advances the committed signer epoch to reflect p's
+// updates, storing it in sinkdb so every core in the cluster sees the
+// same committee take effect at the same height. The commit is a
+// conditional write keyed off the epoch just read, so a second proposal
+// reaching quorum concurrently can't clobber this one: it loses the
+// race for the same epoch number and retries against whichever epoch
+// actually won.
+func (a *API) applySignerChange(ctx context.Context, p *SignerChangeProposal) error {
+	for attempt := 0; attempt < maxEpochCommitAttempts; attempt++ {
+		epoch, err := a.currentSignerEpoch(ctx)
+		if err != nil {
+			return err
+		}
+
+		signers := make(map[string]SignerUpdate)
+		for _, s := range epoch.Signers {
+			signers[string(s.Pubkey)] = s
+		}
+		for _, u := range p.Updates {
+			if u.Power == 0 {
+				delete(signers, string(u.Pubkey))
+			} else {
+				signers[string(u.Pubkey)] = u
+			}
+		}
+
+		next := &SignerEpoch{
+			Epoch:           epoch.Epoch + 1,
+			EffectiveHeight: p.EffectiveHeight,
+		}
+		for _, s := range signers {
+			next.Signers = append(next.Signers, s)
+		}
+
+		b, err := json.Marshal(next)
+		if err != nil {
+			return errors.Wrap(err, "encoding signer epoch")
+		}
+
+		key := signerEpochKeyFor(next.Epoch)
+		err = a.sdb.Exec(ctx, sinkdb.IfNotExists(key), sinkdb.Set(key, &sinkdb.Bytes{Value: b}))
+		if err == nil {
+			return nil
+		}
+		// Lost the race to commit epoch next.Epoch to some other
+		// proposal; loop around, re-read whichever epoch just won, and
+		// retry on top of it.
+	}
+	return errTooManyEpochRetries
+}
+
+// currentSignerEpoch returns the signer committee currently in effect,
+// the highest-numbered epoch under signerEpochPrefix, or the zero epoch
+// if a rotation has never been committed.
+func (a *API) currentSignerEpoch(ctx context.Context) (*SignerEpoch, error) {
+	iter := a.sdb.GetAll(signerEpochPrefix)
+	var latest *SignerEpoch
+	for iter.Next(ctx) {
+		var raw sinkdb.Bytes
+		if err := iter.Value(&raw); err != nil {
+			return nil, errors.Wrap(err, "loading signer epoch")
+		}
+		var epoch SignerEpoch
+		if err := json.Unmarshal(raw.Value, &epoch); err != nil {
+			return nil, errors.Wrap(err, "decoding signer epoch")
+		}
+		if latest == nil || epoch.Epoch > latest.Epoch {
+			latest = &epoch
+		}
+	}
+	if err := iter.Err(); err != nil {
+		return nil, errors.Wrap(err, "loading signer epoch")
+	}
+	if latest == nil {
+		return &SignerEpoch{}, nil
+	}
+	return latest, nil
+}
+
+func (a *API) loadSignerChange(ctx context.Context, id string) (*SignerChangeProposal, error) {
+	var raw sinkdb.Bytes
+	found, err := a.sdb.Get(ctx, signerChangePrefix+id, &raw)
+	if err != nil {
+		return nil, errors.Wrap(err, "loading signer-change proposal")
+	}
+	if !found {
+		return nil, errUnknownChange
+	}
+	var p SignerChangeProposal
+	if err := json.Unmarshal(raw.Value, &p); err != nil {
+		return nil, errors.Wrap(err, "decoding signer-change proposal")
+	}
+	return &p, nil
+}
+
+// listSignerChanges returns every signer-change proposal known to this
+// core, pending or already committed, for operator visibility.
+func (a *API) listSignerChanges(ctx context.Context) ([]*SignerChangeProposal, error) {
+	iter := a.sdb.GetAll(signerChangePrefix)
+	var out []*SignerChangeProposal
+	for iter.Next(ctx) {
+		var raw sinkdb.Bytes
+		if err := iter.Value(&raw); err != nil {
+			return nil, err
+		}
+		var p SignerChangeProposal
+		if err := json.Unmarshal(raw.Value, &p); err != nil {
+			return nil, err
+		}
+		out = append(out, &p)
+	}
+	return out, iter.Err()
+}
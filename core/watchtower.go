@@ -0,0 +1,182 @@
+package core
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"time"
+
+	"chain/errors"
+	"chain/log"
+	"chain/net/http/httpjson"
+	"chain/protocol/bc/legacy"
+	"chain/protocol/validation"
+)
+
+// errNotValidator is returned by the fraud-alert endpoints when the core
+// isn't running in watchtower mode (config.Config.IsValidator).
+var errNotValidator = errors.New("core is not configured as a validator")
+
+// FraudAlert reports a block that failed independent re-validation. It
+// carries a Merkle inclusion proof of the offending transaction against
+// MerkleRoot so subscribers can verify the claim without re-downloading
+// the full block; publishFraudAlert rejects any alert whose proof
+// doesn't check out against its own claimed root.
+type FraudAlert struct {
+	BlockHeight uint64                `json:"block_height"`
+	BlockID     legacy.Hash           `json:"block_id"`
+	FailingTxID legacy.Hash           `json:"failing_tx_id"`
+	Reason      string                `json:"reason"`
+	MerkleRoot  legacy.Hash           `json:"merkle_root"`
+	Proof       *legacy.TxMerkleProof `json:"merkle_proof"`
+	At          time.Time             `json:"at"`
+}
+
+type watchtower struct {
+	mu          sync.Mutex
+	alerts      []*FraudAlert
+	subscribers map[chan *FraudAlert]struct{}
+}
+
+func newWatchtower() *watchtower {
+	return &watchtower{subscribers: make(map[chan *FraudAlert]struct{})}
+}
+
+// theWatchtower is a process-wide singleton, the same way core/fetch
+// tracks generator height and reorg state at package scope rather than
+// on the API struct: a core runs at most one watchtower, and recording
+// an alert or re-validating a block doesn't depend on which request
+// handler is running. This also means the fraud-alert endpoints never
+// see a nil watchtower, regardless of how API got constructed.
+var theWatchtower = newWatchtower()
+
+// ValidateBlock independently re-executes every transaction in b against
+// protocol/validation, the same validation path core uses for new blocks,
+// rather than trusting the generator's signature. On failure it records
+// and broadcasts a FraudAlert and returns an error, so the caller refuses
+// to advance local state past this block. It satisfies fetch.Validator.
+func (w *watchtower) ValidateBlock(ctx context.Context, b *legacy.Block, initialBlockHash legacy.Hash) error {
+	root := legacy.TxMerkleRoot(b)
+	for _, tx := range b.Transactions {
+		err := validation.ValidateTx(tx.Tx, initialBlockHash)
+		if err != nil {
+			proof, proofErr := legacy.NewTxMerkleProof(b, tx.ID)
+			if proofErr != nil {
+				return errors.Wrap(proofErr, "building fraud-alert merkle proof")
+			}
+			alert := &FraudAlert{
+				BlockHeight: b.Height,
+				BlockID:     b.Hash(),
+				FailingTxID: tx.ID,
+				Reason:      err.Error(),
+				MerkleRoot:  root,
+				Proof:       proof,
+				At:          time.Now(),
+			}
+			w.record(ctx, alert)
+			return errors.Wrapf(err, "block %d failed independent validation", b.Height)
+		}
+	}
+	return nil
+}
+
+// record appends alert and broadcasts it to subscribers, unless an alert
+// for the same (BlockHeight, FailingTxID) was already recorded: a block
+// that fails independent validation is re-fetched and re-validated on
+// every poll tick until local state advances past it, so without this
+// check w.alerts would grow unboundedly and subscribers would see the
+// same alert re-broadcast every pollInterval.
+func (w *watchtower) record(ctx context.Context, alert *FraudAlert) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	for _, existing := range w.alerts {
+		if existing.BlockHeight == alert.BlockHeight && existing.FailingTxID == alert.FailingTxID {
+			return
+		}
+	}
+	w.alerts = append(w.alerts, alert)
+	for sub := range w.subscribers {
+		select {
+		case sub <- alert:
+		default:
+			log.Printf(ctx, "fraud alert subscriber channel full, dropping alert for block %d", alert.BlockHeight)
+		}
+	}
+}
+
+// errUnverifiableAlert is returned by publishFraudAlert when the
+// submitted FraudAlert's Merkle proof doesn't verify against its own
+// claimed MerkleRoot and FailingTxID, so a subscriber can trust a
+// stored alert without re-checking the proof itself.
+var errUnverifiableAlert = errors.New("fraud alert's merkle proof does not verify")
+
+// publishFraudAlert accepts a FraudAlert from another core's watchtower
+// over cross-core RPC and merges it into this core's view, so operators
+// subscribed to any validator see the same alerts. The alert is
+// rejected unless its Merkle proof verifies the failing transaction
+// against the alert's own claimed root.
+func (a *API) publishFraudAlert(ctx context.Context, alert *FraudAlert) error {
+	if !a.config.IsValidator {
+		return errNotValidator
+	}
+	if alert.Proof == nil || !alert.Proof.Verify(alert.MerkleRoot, alert.FailingTxID) {
+		return errUnverifiableAlert
+	}
+	theWatchtower.record(ctx, alert)
+	return nil
+}
+
+// listFraudAlerts returns every fraud alert this watchtower has recorded
+// or received, most recent first.
+func (a *API) listFraudAlerts(ctx context.Context) ([]*FraudAlert, error) {
+	if !a.config.IsValidator {
+		return nil, errNotValidator
+	}
+	theWatchtower.mu.Lock()
+	defer theWatchtower.mu.Unlock()
+	out := make([]*FraudAlert, len(theWatchtower.alerts))
+	for i, al := range theWatchtower.alerts {
+		out[len(out)-1-i] = al
+	}
+	return out, nil
+}
+
+// subscribeFraudAlerts streams fraud alerts to the client as Server-Sent
+// Events as they're recorded, so operators don't have to poll
+// /list-fraud-alerts.
+func (a *API) subscribeFraudAlerts(ctx context.Context) error {
+	if !a.config.IsValidator {
+		return errNotValidator
+	}
+
+	w := httpjson.ResponseWriter(ctx)
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		return errors.New("streaming unsupported")
+	}
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+
+	ch := make(chan *FraudAlert, 16)
+	theWatchtower.mu.Lock()
+	theWatchtower.subscribers[ch] = struct{}{}
+	theWatchtower.mu.Unlock()
+	defer func() {
+		theWatchtower.mu.Lock()
+		delete(theWatchtower.subscribers, ch)
+		theWatchtower.mu.Unlock()
+	}()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case alert := <-ch:
+			err := httpjson.WriteSSE(w, "fraud-alert", alert)
+			if err != nil {
+				return err
+			}
+			flusher.Flush()
+		}
+	}
+}
@@ -0,0 +1,236 @@
+package legacy
+
+import (
+	"chain/errors"
+)
+
+// errTruncated is returned by UnmarshalBinary when the input ends before
+// a length-prefixed field has been fully read.
+var errTruncated = errors.New("truncated legacy tx encoding")
+
+// MarshalBinary encodes d using the same length-prefixed scheme
+// MapVMTx's push helpers use for the txvm proof stream, so a TxData and
+// the txvm.Tx it maps to can be persisted and hashed deterministically
+// with a consistent encoding style.
+func (d *TxData) MarshalBinary() ([]byte, error) {
+	var buf []byte
+	pushInt64(&buf, int64(d.MinTime))
+	pushInt64(&buf, int64(d.MaxTime))
+
+	pushInt64(&buf, int64(len(d.Inputs)))
+	for _, in := range d.Inputs {
+		b, err := marshalTxInput(in)
+		if err != nil {
+			return nil, err
+		}
+		pushBytes(&buf, b)
+	}
+
+	pushInt64(&buf, int64(len(d.Outputs)))
+	for _, out := range d.Outputs {
+		pushID(&buf, out.AssetId.Byte32())
+		pushInt64(&buf, int64(out.Amount))
+		pushBytes(&buf, out.ControlProgram)
+		pushBytes(&buf, out.ReferenceData)
+	}
+
+	return buf, nil
+}
+
+// UnmarshalBinary is the inverse of MarshalBinary.
+func (d *TxData) UnmarshalBinary(b []byte) error {
+	r := &proofReader{buf: b}
+
+	minTime, err := r.popInt64()
+	if err != nil {
+		return errors.Wrap(errTruncated, err.Error())
+	}
+	maxTime, err := r.popInt64()
+	if err != nil {
+		return errors.Wrap(errTruncated, err.Error())
+	}
+
+	ninputs, err := r.popInt64()
+	if err != nil {
+		return errors.Wrap(errTruncated, err.Error())
+	}
+	inputs := make([]*TxInput, 0, ninputs)
+	for i := int64(0); i < ninputs; i++ {
+		raw, err := r.popBytes()
+		if err != nil {
+			return errors.Wrap(errTruncated, err.Error())
+		}
+		in, err := unmarshalTxInput(raw)
+		if err != nil {
+			return err
+		}
+		inputs = append(inputs, in)
+	}
+
+	noutputs, err := r.popInt64()
+	if err != nil {
+		return errors.Wrap(errTruncated, err.Error())
+	}
+	outputs := make([]*TxOutput, 0, noutputs)
+	for i := int64(0); i < noutputs; i++ {
+		assetID, err := r.popID()
+		if err != nil {
+			return errors.Wrap(errTruncated, err.Error())
+		}
+		amount, err := r.popInt64()
+		if err != nil {
+			return errors.Wrap(errTruncated, err.Error())
+		}
+		controlProgram, err := r.popBytes()
+		if err != nil {
+			return errors.Wrap(errTruncated, err.Error())
+		}
+		refData, err := r.popBytes()
+		if err != nil {
+			return errors.Wrap(errTruncated, err.Error())
+		}
+		out := &TxOutput{ControlProgram: controlProgram, ReferenceData: refData}
+		out.AssetId = assetID
+		out.Amount = uint64(amount)
+		outputs = append(outputs, out)
+	}
+
+	d.MinTime = uint64(minTime)
+	d.MaxTime = uint64(maxTime)
+	d.Inputs = inputs
+	d.Outputs = outputs
+	return nil
+}
+
+// Input type tags for the MarshalBinary encoding.
+const (
+	inputTypeIssuance = 0
+	inputTypeSpend    = 1
+)
+
+func marshalTxInput(in *TxInput) ([]byte, error) {
+	var buf []byte
+	switch ti := in.TypedInput.(type) {
+	case *IssuanceInput:
+		pushInt64(&buf, inputTypeIssuance)
+		pushBytes(&buf, ti.Nonce)
+		pushBytes(&buf, ti.AssetDefinition)
+		pushID(&buf, ti.InitialBlock.Byte32())
+		pushInt64(&buf, int64(ti.Amount))
+		pushBytes(&buf, ti.IssuanceProgram)
+		pushInt64(&buf, int64(len(ti.Arguments)))
+		for _, arg := range ti.Arguments {
+			pushBytes(&buf, arg)
+		}
+	case *SpendInput:
+		pushInt64(&buf, inputTypeSpend)
+		pushID(&buf, ti.SourceID.Byte32())
+		pushID(&buf, ti.AssetAmount.AssetId.Byte32())
+		pushInt64(&buf, int64(ti.AssetAmount.Amount))
+		pushInt64(&buf, int64(ti.SourcePosition))
+		pushBytes(&buf, ti.ControlProgram)
+		pushInt64(&buf, int64(ti.VMVersion))
+		pushID(&buf, ti.RefDataHash.Byte32())
+		pushInt64(&buf, int64(len(ti.Arguments)))
+		for _, arg := range ti.Arguments {
+			pushBytes(&buf, arg)
+		}
+	default:
+		return nil, errors.New("unknown input type")
+	}
+	pushBytes(&buf, in.ReferenceData)
+	return buf, nil
+}
+
+func unmarshalTxInput(raw []byte) (*TxInput, error) {
+	r := &proofReader{buf: raw}
+
+	typ, err := r.popInt64()
+	if err != nil {
+		return nil, errors.Wrap(errTruncated, err.Error())
+	}
+
+	var typedInput TypedInput
+	switch typ {
+	case inputTypeIssuance:
+		ii := &IssuanceInput{}
+		if ii.Nonce, err = r.popBytes(); err != nil {
+			return nil, errors.Wrap(errTruncated, err.Error())
+		}
+		if ii.AssetDefinition, err = r.popBytes(); err != nil {
+			return nil, errors.Wrap(errTruncated, err.Error())
+		}
+		if ii.InitialBlock, err = r.popID(); err != nil {
+			return nil, errors.Wrap(errTruncated, err.Error())
+		}
+		amount, err := r.popInt64()
+		if err != nil {
+			return nil, errors.Wrap(errTruncated, err.Error())
+		}
+		ii.Amount = uint64(amount)
+		if ii.IssuanceProgram, err = r.popBytes(); err != nil {
+			return nil, errors.Wrap(errTruncated, err.Error())
+		}
+		nargs, err := r.popInt64()
+		if err != nil {
+			return nil, errors.Wrap(errTruncated, err.Error())
+		}
+		for i := int64(0); i < nargs; i++ {
+			arg, err := r.popBytes()
+			if err != nil {
+				return nil, errors.Wrap(errTruncated, err.Error())
+			}
+			ii.Arguments = append(ii.Arguments, arg)
+		}
+		typedInput = ii
+	case inputTypeSpend:
+		si := &SpendInput{}
+		if si.SourceID, err = r.popID(); err != nil {
+			return nil, errors.Wrap(errTruncated, err.Error())
+		}
+		if si.AssetAmount.AssetId, err = r.popID(); err != nil {
+			return nil, errors.Wrap(errTruncated, err.Error())
+		}
+		amount, err := r.popInt64()
+		if err != nil {
+			return nil, errors.Wrap(errTruncated, err.Error())
+		}
+		si.AssetAmount.Amount = uint64(amount)
+		pos, err := r.popInt64()
+		if err != nil {
+			return nil, errors.Wrap(errTruncated, err.Error())
+		}
+		si.SourcePosition = uint64(pos)
+		if si.ControlProgram, err = r.popBytes(); err != nil {
+			return nil, errors.Wrap(errTruncated, err.Error())
+		}
+		vmv, err := r.popInt64()
+		if err != nil {
+			return nil, errors.Wrap(errTruncated, err.Error())
+		}
+		si.VMVersion = uint64(vmv)
+		if si.RefDataHash, err = r.popID(); err != nil {
+			return nil, errors.Wrap(errTruncated, err.Error())
+		}
+		nargs, err := r.popInt64()
+		if err != nil {
+			return nil, errors.Wrap(errTruncated, err.Error())
+		}
+		for i := int64(0); i < nargs; i++ {
+			arg, err := r.popBytes()
+			if err != nil {
+				return nil, errors.Wrap(errTruncated, err.Error())
+			}
+			si.Arguments = append(si.Arguments, arg)
+		}
+		typedInput = si
+	default:
+		return nil, errors.New("unknown input type tag")
+	}
+
+	refData, err := r.popBytes()
+	if err != nil {
+		return nil, errors.Wrap(errTruncated, err.Error())
+	}
+	return &TxInput{ReferenceData: refData, TypedInput: typedInput}, nil
+}
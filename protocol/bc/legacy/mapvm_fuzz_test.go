@@ -0,0 +1,137 @@
+//go:build fuzz
+// +build fuzz
+
+package legacy
+
+import (
+	"math/rand"
+	"testing"
+
+	"chain/protocol/bc"
+	"chain/protocol/validation"
+	"chain/protocol/vm"
+)
+
+// TestMapUnmapRoundTrip generates random legacy transactions, maps them
+// to txvm.Tx, unmaps back to TxData, re-maps, and asserts the two
+// txvm.Tx values are byte-identical and agree on ValidateTx against a
+// fixed initial block hash. This locks in the MapVMTx/UnmapVMTx contract
+// so external indexers can keep consuming the old schema during a
+// phased rollout. Run with: go test -tags=fuzz ./protocol/bc/legacy
+func TestMapUnmapRoundTrip(t *testing.T) {
+	var initialBlockHash bc.Hash
+
+	rnd := rand.New(rand.NewSource(1))
+	for i := 0; i < 200; i++ {
+		txData := randomTxData(rnd)
+
+		tx1 := MapVMTx(txData)
+		b1, err := tx1.MarshalBinary()
+		if err != nil {
+			t.Fatalf("tx1.MarshalBinary: %s", err)
+		}
+
+		unmapped, err := UnmapVMTx(tx1)
+		if err != nil {
+			t.Fatalf("UnmapVMTx: %s", err)
+		}
+
+		tx2 := MapVMTx(unmapped)
+		b2, err := tx2.MarshalBinary()
+		if err != nil {
+			t.Fatalf("tx2.MarshalBinary: %s", err)
+		}
+
+		if !bytesEqual(b1, b2) {
+			t.Fatalf("map -> unmap -> map is not byte-identical on iteration %d", i)
+		}
+
+		err1 := validation.ValidateTx(tx1, initialBlockHash)
+		err2 := validation.ValidateTx(tx2, initialBlockHash)
+		if (err1 == nil) != (err2 == nil) {
+			t.Fatalf("ValidateTx disagreement on iteration %d: %v vs %v", i, err1, err2)
+		}
+	}
+}
+
+func bytesEqual(a, b []byte) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// randomTxData builds a small random legacy transaction covering both
+// issuance (with and without a nonce) and spend inputs, and both lock
+// and retire outputs, since those are the cases MapVMTx branches on.
+// Program and control-program lengths are randomized, including the
+// 32-byte length that collides with an id's encoded length, so the
+// mix exercises UnmapVMTx's position-based field parsing rather than
+// only the lengths it's easiest to get right. Asset definitions and
+// reference data are left empty: MapVMTx writes only their hashes into
+// the proof, so UnmapVMTx returns them nil.
+func randomTxData(rnd *rand.Rand) *TxData {
+	now := uint64(1700000000000)
+	txData := &TxData{
+		MinTime: now,
+		MaxTime: now + 1000,
+	}
+
+	if rnd.Intn(2) == 0 {
+		txData.Inputs = append(txData.Inputs, &TxInput{
+			TypedInput: &IssuanceInput{
+				Nonce:           randomBytes(rnd, 8),
+				Amount:          uint64(rnd.Intn(1000) + 1),
+				IssuanceProgram: randomBytes(rnd, randomProgramLen(rnd)),
+			},
+		})
+	} else {
+		txData.Inputs = append(txData.Inputs, &TxInput{
+			TypedInput: &IssuanceInput{
+				Amount:          uint64(rnd.Intn(1000) + 1),
+				IssuanceProgram: randomBytes(rnd, randomProgramLen(rnd)),
+			},
+		})
+	}
+
+	si := &SpendInput{
+		ControlProgram: randomBytes(rnd, randomProgramLen(rnd)),
+		SourcePosition: uint64(rnd.Intn(10)),
+		VMVersion:      1,
+	}
+	si.AssetAmount.Amount = uint64(rnd.Intn(1000) + 1)
+	copy(si.AssetAmount.AssetId[:], randomBytes(rnd, 32))
+	copy(si.SourceID[:], randomBytes(rnd, 32))
+	copy(si.RefDataHash[:], randomBytes(rnd, 32))
+	txData.Inputs = append(txData.Inputs, &TxInput{TypedInput: si})
+
+	out := &TxOutput{ControlProgram: randomBytes(rnd, randomProgramLen(rnd))}
+	out.Amount = uint64(rnd.Intn(1000) + 1)
+	txData.Outputs = append(txData.Outputs, out)
+
+	retired := &TxOutput{ControlProgram: []byte{byte(vm.OP_FAIL)}}
+	retired.Amount = uint64(rnd.Intn(1000) + 1)
+	txData.Outputs = append(txData.Outputs, retired)
+
+	return txData
+}
+
+// randomProgramLen returns a program length that sometimes lands on 32
+// bytes, the length an id is encoded with, to exercise the boundary
+// where a naive type-sniffing parse would misclassify a program as an
+// id.
+func randomProgramLen(rnd *rand.Rand) int {
+	lens := []int{4, 12, 32, 40}
+	return lens[rnd.Intn(len(lens))]
+}
+
+func randomBytes(rnd *rand.Rand, n int) []byte {
+	b := make([]byte, n)
+	rnd.Read(b)
+	return b
+}
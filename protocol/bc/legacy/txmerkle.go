@@ -0,0 +1,113 @@
+package legacy
+
+import (
+	"crypto/sha256"
+
+	"chain/errors"
+)
+
+// errTxNotInBlock is returned by NewTxMerkleProof when the requested
+// transaction isn't one of the block's transactions.
+var errTxNotInBlock = errors.New("transaction is not in block")
+
+// TxMerkleProof is a Merkle inclusion proof of a single transaction
+// against a block's transaction Merkle root. A watchtower (see
+// core/watchtower.go) attaches one to every FraudAlert it publishes, so
+// a subscriber can confirm the offending transaction was really part of
+// the block without downloading the full block.
+type TxMerkleProof struct {
+	Index  int    `json:"index"`  // position of the leaf among the block's transactions
+	Total  int    `json:"total"`  // number of transactions in the block
+	Hashes []Hash `json:"hashes"` // sibling hashes, leaf to root
+}
+
+// TxMerkleRoot computes the Merkle root over b's transaction IDs, the
+// root that NewTxMerkleProof's proofs verify against.
+func TxMerkleRoot(b *Block) Hash {
+	level := make([]Hash, len(b.Transactions))
+	for i, tx := range b.Transactions {
+		level[i] = tx.ID
+	}
+	for len(level) > 1 {
+		level = nextMerkleLevel(level)
+	}
+	if len(level) == 0 {
+		return Hash{}
+	}
+	return level[0]
+}
+
+// NewTxMerkleProof builds an inclusion proof for the transaction txID
+// within b.
+func NewTxMerkleProof(b *Block, txID Hash) (*TxMerkleProof, error) {
+	level := make([]Hash, len(b.Transactions))
+	index := -1
+	for i, tx := range b.Transactions {
+		level[i] = tx.ID
+		if tx.ID == txID {
+			index = i
+		}
+	}
+	if index < 0 {
+		return nil, errTxNotInBlock
+	}
+
+	p := &TxMerkleProof{Index: index, Total: len(level)}
+	for len(level) > 1 {
+		var sibling Hash
+		if index%2 == 0 {
+			sibling = level[index]
+			if index+1 < len(level) {
+				sibling = level[index+1]
+			}
+		} else {
+			sibling = level[index-1]
+		}
+		p.Hashes = append(p.Hashes, sibling)
+
+		level = nextMerkleLevel(level)
+		index /= 2
+	}
+	return p, nil
+}
+
+// Verify reports whether p is a valid inclusion proof of leaf against
+// root: walking p's sibling hashes up from leaf reproduces root.
+func (p *TxMerkleProof) Verify(root, leaf Hash) bool {
+	cur := leaf
+	index := p.Index
+	for _, sibling := range p.Hashes {
+		if index%2 == 0 {
+			cur = hashTxMerklePair(cur, sibling)
+		} else {
+			cur = hashTxMerklePair(sibling, cur)
+		}
+		index /= 2
+	}
+	return cur == root
+}
+
+// nextMerkleLevel hashes level's leaves pairwise, duplicating the last
+// leaf when level has an odd length, producing the next level up the
+// tree.
+func nextMerkleLevel(level []Hash) []Hash {
+	next := make([]Hash, (len(level)+1)/2)
+	for i := range next {
+		left := level[2*i]
+		right := left
+		if 2*i+1 < len(level) {
+			right = level[2*i+1]
+		}
+		next[i] = hashTxMerklePair(left, right)
+	}
+	return next
+}
+
+func hashTxMerklePair(l, r Hash) Hash {
+	h := sha256.New()
+	h.Write(l[:])
+	h.Write(r[:])
+	var out Hash
+	copy(out[:], h.Sum(nil))
+	return out
+}
@@ -0,0 +1,434 @@
+package legacy
+
+import (
+	"encoding/binary"
+
+	"chain/errors"
+	"chain/protocol/txvm"
+	"chain/protocol/txvm/op"
+	"chain/protocol/vm"
+)
+
+// ErrBadProof is returned by UnmapVMTx when a tx's Proof program doesn't
+// match the exact opcode grammar emitted by MapVMTx. UnmapVMTx is only
+// defined for txs produced by MapVMTx; anything else is rejected rather
+// than guessed at.
+var ErrBadProof = errors.New("txvm proof does not match legacy mapping grammar")
+
+// proofReader walks a txvm.Tx's Proof program front-to-back. It's the
+// exact inverse of the push* helpers in mapvm.go: those append
+// length-prefixed data items and single-byte opcodes to the end of a
+// growing program; proofReader pops them off the front in the order
+// MapVMTx wrote them.
+type proofReader struct {
+	buf []byte
+}
+
+func (r *proofReader) done() bool { return len(r.buf) == 0 }
+
+func (r *proofReader) popByte() (byte, error) {
+	if len(r.buf) < 1 {
+		return 0, errors.New("unexpected end of proof")
+	}
+	b := r.buf[0]
+	r.buf = r.buf[1:]
+	return b, nil
+}
+
+// popBytes pops one data() item: a varint length (offset by op.BaseData)
+// followed by that many raw bytes.
+func (r *proofReader) popBytes() ([]byte, error) {
+	n, used := binary.Uvarint(r.buf)
+	if used <= 0 {
+		return nil, errors.New("bad length prefix")
+	}
+	if int64(n) < op.BaseData {
+		return nil, errors.New("length prefix smaller than op.BaseData")
+	}
+	dataLen := int64(n) - op.BaseData
+	rest := r.buf[used:]
+	if int64(len(rest)) < dataLen {
+		return nil, errors.New("unexpected end of proof")
+	}
+	out := rest[:dataLen]
+	r.buf = rest[dataLen:]
+	return out, nil
+}
+
+// popInt64 pops a pushInt64 item: a data() item holding a varint, then
+// the op.Varint opcode.
+func (r *proofReader) popInt64() (int64, error) {
+	data, err := r.popBytes()
+	if err != nil {
+		return 0, err
+	}
+	v, used := binary.Uvarint(data)
+	if used <= 0 {
+		return 0, errors.New("bad varint payload")
+	}
+	got, err := r.popByte()
+	if err != nil {
+		return 0, err
+	}
+	if got != op.Varint {
+		return 0, errors.New("expected op.Varint")
+	}
+	return int64(v), nil
+}
+
+func (r *proofReader) popID() (id [32]byte, err error) {
+	b, err := r.popBytes()
+	if err != nil {
+		return id, err
+	}
+	if len(b) != 32 {
+		return id, errors.New("expected 32-byte id")
+	}
+	copy(id[:], b)
+	return id, nil
+}
+
+// extractNonce recovers the original issuance nonce from the vm1 anchor
+// program MapVMTx builds for it: AddData(nonce), OP_DROP, OP_ASSET,
+// AddData(assetID), OP_EQUAL.
+func extractNonce(prog []byte) ([]byte, error) {
+	insts, err := vm.ParseProgram(prog)
+	if err != nil {
+		return nil, err
+	}
+	if len(insts) != 5 {
+		return nil, errors.New("anchor program has unexpected length")
+	}
+	if insts[1].Op != vm.OP_DROP {
+		return nil, errors.New("anchor program missing OP_DROP")
+	}
+	if insts[2].Op != vm.OP_ASSET {
+		return nil, errors.New("anchor program missing OP_ASSET")
+	}
+	return insts[0].Data, nil
+}
+
+// legacyInput holds the fields UnmapVMTx has recovered for one input,
+// before it's turned into the concrete IssuanceInput/SpendInput. ids and
+// ints are in the order MapVMTx pushed them.
+type legacyInput struct {
+	isIssuance bool
+	nonce      []byte
+	ids        [][32]byte
+	ints       []int64
+	program    []byte
+}
+
+// UnmapVMTx is the inverse of MapVMTx: it walks tx's proof program and
+// reconstructs the legacy TxData it was mapped from, rejecting proofs
+// that don't match the exact grammar MapVMTx emits. Reference data is
+// only recoverable as its hash, since MapVMTx never puts the original
+// bytes in the proof; UnmapVMTx leaves TxData.Inputs[i].ReferenceData
+// and the issuance AssetDefinition nil in that case. Input Arguments are
+// not recovered (the trailing argument programs are not parsed), and
+// every SpendInput comes back with VMVersion 1.
+func UnmapVMTx(tx *txvm.Tx) (*TxData, error) {
+	r := &proofReader{buf: tx.Proof}
+
+	var inputs []legacyInput
+	for {
+		in, isCount, count, err := parseOneInput(r)
+		if err != nil {
+			return nil, errors.Wrap(ErrBadProof, err.Error())
+		}
+		if isCount {
+			// parseOneInput already consumed the op.VM1Mux terminator
+			// along with the count item.
+			if int(count) != len(inputs) {
+				return nil, errors.Wrap(ErrBadProof, "input count does not match number of inputs parsed")
+			}
+			break
+		}
+		inputs = append(inputs, in)
+	}
+
+	txd := &TxData{
+		MinTime: tx.MinTime,
+		MaxTime: tx.MaxTime,
+	}
+	for _, in := range inputs {
+		if in.isIssuance {
+			// ids, in push order: assetDefHash, InitialBlock, refDataHash, AssetID.
+			// ints: Amount.
+			if len(in.ids) != 4 || len(in.ints) != 1 {
+				return nil, errors.Wrap(ErrBadProof, "issuance proof has unexpected field counts")
+			}
+			ii := &IssuanceInput{
+				Nonce:           in.nonce,
+				IssuanceProgram: in.program,
+				Amount:          uint64(in.ints[0]),
+			}
+			ii.InitialBlock = in.ids[1]
+			txd.Inputs = append(txd.Inputs, &TxInput{TypedInput: ii})
+		} else {
+			// ids, in push order: RefDataHash, AssetId, SourceID, refDataHash(input).
+			// ints, in push order: SourcePosition, Amount.
+			if len(in.ids) != 4 || len(in.ints) != 2 {
+				return nil, errors.Wrap(ErrBadProof, "spend proof has unexpected field counts")
+			}
+			si := &SpendInput{
+				ControlProgram: in.program,
+				SourcePosition: uint64(in.ints[0]),
+				VMVersion:      1,
+			}
+			si.AssetAmount.Amount = uint64(in.ints[1])
+			si.AssetAmount.AssetId = in.ids[1]
+			si.SourceID = in.ids[2]
+			si.RefDataHash = in.ids[0]
+			txd.Inputs = append(txd.Inputs, &TxInput{TypedInput: si})
+		}
+	}
+
+	// Outputs were written in reverse order (output N-1 first); collect
+	// then reverse so TxData.Outputs comes back in original order.
+	var outputsRev []*TxOutput
+	for !r.done() {
+		out, ok := parseOneOutput(r)
+		if !ok {
+			break // remaining bytes are the args programs, not an output
+		}
+		outputsRev = append(outputsRev, out)
+	}
+	for i := len(outputsRev) - 1; i >= 0; i-- {
+		txd.Outputs = append(txd.Outputs, outputsRev[i])
+	}
+
+	return txd, nil
+}
+
+// parseOneInput parses either one issuance, one spend, or (when isCount
+// is true) the trailing input-count item that precedes op.VM1Mux.
+func parseOneInput(r *proofReader) (in legacyInput, isCount bool, count int64, err error) {
+	save := *r
+
+	// Try the nonce-bearing issuance prefix: minTime, maxTime, prog, then op.Anchor.
+	var nonce []byte
+	if _, err1 := r.popInt64(); err1 == nil {
+		if _, err2 := r.popInt64(); err2 == nil {
+			if prog, err3 := r.popBytes(); err3 == nil {
+				if b, err4 := r.popByte(); err4 == nil && b == op.Anchor {
+					nonce, err = extractNonce(prog)
+					if err != nil {
+						return in, false, 0, err
+					}
+				} else {
+					*r = save
+				}
+			} else {
+				*r = save
+			}
+		} else {
+			*r = save
+		}
+	} else {
+		*r = save
+	}
+
+	// Could be: the trailing count item (a single pushInt64 followed by
+	// op.VM1Mux), an issuance (assetDefHash, IssuanceProgram,
+	// InitialBlock, refDataHash(input), Amount, AssetID, then
+	// op.VM1Issue), or a spend (RefDataHash, ControlProgram,
+	// SourcePosition, Amount, AssetId, SourceID, refDataHash(input),
+	// then op.VM1Unlock). Each shape is matched by fixed position, not
+	// by sniffing each item's encoded length: trying popID before
+	// popBytes on a generic item misclassifies any program that happens
+	// to be exactly 32 bytes, so there's no general item loop here.
+	countSave := *r
+	if n, errN := r.popInt64(); errN == nil {
+		if b, errB := r.popByte(); errB == nil && b == op.VM1Mux {
+			return legacyInput{}, true, n, nil
+		}
+	}
+	*r = countSave
+
+	if li, ok := parseIssuanceFields(r, nonce); ok {
+		return li, false, 0, nil
+	}
+	if li, ok := parseSpendFields(r); ok {
+		return li, false, 0, nil
+	}
+	return in, false, 0, errors.New("unrecognized input terminator")
+}
+
+// parseIssuanceFields tries to pop the fixed issuance field sequence
+// MapVMTx emits (see MapVMTx's IssuanceInput case): assetDefHash,
+// IssuanceProgram, InitialBlock, refDataHash(input), Amount, AssetID,
+// then op.VM1Issue. It restores r and reports ok=false on any mismatch.
+func parseIssuanceFields(r *proofReader, nonce []byte) (legacyInput, bool) {
+	save := *r
+
+	assetDefHash, err := r.popID()
+	if err != nil {
+		*r = save
+		return legacyInput{}, false
+	}
+	prog, err := r.popBytes()
+	if err != nil {
+		*r = save
+		return legacyInput{}, false
+	}
+	initialBlock, err := r.popID()
+	if err != nil {
+		*r = save
+		return legacyInput{}, false
+	}
+	refDataHash, err := r.popID()
+	if err != nil {
+		*r = save
+		return legacyInput{}, false
+	}
+	amount, err := r.popInt64()
+	if err != nil {
+		*r = save
+		return legacyInput{}, false
+	}
+	assetID, err := r.popID()
+	if err != nil {
+		*r = save
+		return legacyInput{}, false
+	}
+	term, err := r.popByte()
+	if err != nil || term != op.VM1Issue {
+		*r = save
+		return legacyInput{}, false
+	}
+
+	return legacyInput{
+		isIssuance: true,
+		nonce:      nonce,
+		ids:        [][32]byte{assetDefHash, initialBlock, refDataHash, assetID},
+		ints:       []int64{amount},
+		program:    prog,
+	}, true
+}
+
+// parseSpendFields tries to pop the fixed spend field sequence MapVMTx
+// emits (see MapVMTx's SpendInput case): RefDataHash, ControlProgram,
+// SourcePosition, Amount, AssetId, SourceID, refDataHash(input), then
+// op.VM1Unlock. It restores r and reports ok=false on any mismatch.
+func parseSpendFields(r *proofReader) (legacyInput, bool) {
+	save := *r
+
+	refDataHash, err := r.popID()
+	if err != nil {
+		*r = save
+		return legacyInput{}, false
+	}
+	prog, err := r.popBytes()
+	if err != nil {
+		*r = save
+		return legacyInput{}, false
+	}
+	sourcePosition, err := r.popInt64()
+	if err != nil {
+		*r = save
+		return legacyInput{}, false
+	}
+	amount, err := r.popInt64()
+	if err != nil {
+		*r = save
+		return legacyInput{}, false
+	}
+	assetID, err := r.popID()
+	if err != nil {
+		*r = save
+		return legacyInput{}, false
+	}
+	sourceID, err := r.popID()
+	if err != nil {
+		*r = save
+		return legacyInput{}, false
+	}
+	inputRefDataHash, err := r.popID()
+	if err != nil {
+		*r = save
+		return legacyInput{}, false
+	}
+	term, err := r.popByte()
+	if err != nil || term != op.VM1Unlock {
+		*r = save
+		return legacyInput{}, false
+	}
+
+	return legacyInput{
+		ids:     [][32]byte{refDataHash, assetID, sourceID, inputRefDataHash},
+		ints:    []int64{sourcePosition, amount},
+		program: prog,
+	}, true
+}
+
+// parseOneOutput tries to parse one output off the front of r. Outputs
+// carry no explicit count the way inputs do, so UnmapVMTx can't tell in
+// advance when the last one has been read: it just keeps calling
+// parseOneOutput until one fails to match. A per-input args program
+// (pushInt64(nargs), op.List, op.Satisfy) happens to start with the same
+// shape as an output's Amount field, so a mismatch can surface several
+// pops in rather than on the very first one; on any mismatch, at any
+// point, parseOneOutput restores r and reports ok=false rather than
+// treating it as a malformed proof.
+func parseOneOutput(r *proofReader) (*TxOutput, bool) {
+	save := *r
+
+	amount, err := r.popInt64()
+	if err != nil {
+		*r = save
+		return nil, false
+	}
+	assetID, err := r.popID()
+	if err != nil {
+		*r = save
+		return nil, false
+	}
+	withdrawOp, err := r.popByte()
+	if err != nil || withdrawOp != op.VM1Withdraw {
+		*r = save
+		return nil, false
+	}
+	if _, err := r.popID(); err != nil { // reference-data hash; original bytes unrecoverable
+		*r = save
+		return nil, false
+	}
+
+	// A locked output pushes its control program before op.Lock; a
+	// retired output pushes nothing before op.Retire.
+	var controlProgram []byte
+	beforeProgram := *r
+	if prog, err := r.popBytes(); err == nil {
+		controlProgram = prog
+	} else {
+		*r = beforeProgram
+	}
+
+	lockOrRetire, err := r.popByte()
+	if err != nil {
+		*r = save
+		return nil, false
+	}
+
+	out := &TxOutput{}
+	out.Amount = uint64(amount)
+	out.AssetId = assetID
+	switch lockOrRetire {
+	case op.Retire:
+		if controlProgram != nil {
+			*r = save
+			return nil, false
+		}
+		out.ControlProgram = []byte{byte(vm.OP_FAIL)}
+	case op.Lock:
+		if controlProgram == nil {
+			*r = save
+			return nil, false
+		}
+		out.ControlProgram = controlProgram
+	default:
+		*r = save
+		return nil, false
+	}
+	return out, true
+}
@@ -0,0 +1,129 @@
+package txvm
+
+import (
+	"encoding/binary"
+
+	"chain/errors"
+)
+
+// errTruncated is returned by Tx.UnmarshalBinary when the input ends
+// before a length-prefixed field has been fully read.
+var errTruncated = errors.New("truncated txvm tx encoding")
+
+// MarshalBinary encodes tx using a stable length-prefixed format, so a
+// txvm.Tx can be persisted and hashed deterministically, and so
+// legacy.UnmapVMTx's property test can compare two txvm.Tx values for
+// byte-for-byte equality.
+func (tx *Tx) MarshalBinary() ([]byte, error) {
+	var buf []byte
+	putVarint(&buf, int64(tx.MinTime))
+	putVarint(&buf, int64(tx.MaxTime))
+
+	putVarint(&buf, int64(len(tx.Nonce)))
+	for _, n := range tx.Nonce {
+		putBytes(&buf, n[:])
+	}
+
+	putVarint(&buf, int64(len(tx.In)))
+	for _, in := range tx.In {
+		putBytes(&buf, in[:])
+	}
+
+	putBytes(&buf, tx.Proof)
+	return buf, nil
+}
+
+// UnmarshalBinary is the inverse of MarshalBinary.
+func (tx *Tx) UnmarshalBinary(b []byte) error {
+	minTime, b, err := getVarint(b)
+	if err != nil {
+		return errors.Wrap(errTruncated, err.Error())
+	}
+	maxTime, b, err := getVarint(b)
+	if err != nil {
+		return errors.Wrap(errTruncated, err.Error())
+	}
+
+	nnonce, b, err := getVarint(b)
+	if err != nil {
+		return errors.Wrap(errTruncated, err.Error())
+	}
+	nonces := make([]ID, 0, nnonce)
+	for i := int64(0); i < nnonce; i++ {
+		var item []byte
+		item, b, err = getBytes(b)
+		if err != nil {
+			return errors.Wrap(errTruncated, err.Error())
+		}
+		if len(item) != 32 {
+			return errors.Wrap(errTruncated, "nonce id must be 32 bytes")
+		}
+		var id ID
+		copy(id[:], item)
+		nonces = append(nonces, id)
+	}
+
+	nin, b, err := getVarint(b)
+	if err != nil {
+		return errors.Wrap(errTruncated, err.Error())
+	}
+	ins := make([][32]byte, 0, nin)
+	for i := int64(0); i < nin; i++ {
+		var item []byte
+		item, b, err = getBytes(b)
+		if err != nil {
+			return errors.Wrap(errTruncated, err.Error())
+		}
+		if len(item) != 32 {
+			return errors.Wrap(errTruncated, "input id must be 32 bytes")
+		}
+		var id [32]byte
+		copy(id[:], item)
+		ins = append(ins, id)
+	}
+
+	proof, b, err := getBytes(b)
+	if err != nil {
+		return errors.Wrap(errTruncated, err.Error())
+	}
+	if len(b) != 0 {
+		return errors.Wrap(errTruncated, "trailing bytes after proof")
+	}
+
+	tx.MinTime = uint64(minTime)
+	tx.MaxTime = uint64(maxTime)
+	tx.Nonce = nonces
+	tx.In = ins
+	tx.Proof = proof
+	return nil
+}
+
+func putVarint(buf *[]byte, n int64) {
+	var tmp [binary.MaxVarintLen64]byte
+	written := binary.PutUvarint(tmp[:], uint64(n))
+	*buf = append(*buf, tmp[:written]...)
+}
+
+func putBytes(buf *[]byte, p []byte) {
+	putVarint(buf, int64(len(p)))
+	*buf = append(*buf, p...)
+}
+
+func getVarint(b []byte) (int64, []byte, error) {
+	v, n := binary.Uvarint(b)
+	if n <= 0 {
+		return 0, nil, errors.New("bad varint")
+	}
+	return int64(v), b[n:], nil
+}
+
+func getBytes(b []byte) ([]byte, []byte, error) {
+	n, rest, err := getVarint(b)
+	if err != nil {
+		return nil, nil, err
+	}
+	if int64(len(rest)) < n {
+		return nil, nil, errors.New("unexpected end of input")
+	}
+	return rest[:n], rest[n:], nil
+}